@@ -0,0 +1,517 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	awsbase "github.com/hashicorp/aws-sdk-go-base/v2"
+	"github.com/hashicorp/terraform/internal/configs/configschema"
+	"github.com/hashicorp/terraform/internal/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+const (
+	// assumeRoleMinDuration and assumeRoleMaxDuration mirror the session
+	// duration limits documented for STS AssumeRole: 900 seconds (15
+	// minutes) to 43200 seconds (12 hours).
+	assumeRoleMinDuration = 15 * time.Minute
+	assumeRoleMaxDuration = 12 * time.Hour
+
+	sessionNamePattern = `[\w+=,.@-]+`
+	externalIDPattern  = `[\w+=,.@:/-]+`
+	tagPattern         = `[\w+=,.@:/-]+`
+)
+
+var (
+	roleArnRegexp     = regexp.MustCompile(`^arn:[^:]+:iam::[0-9]{12}:role/.+$`)
+	policyArnRegexp   = regexp.MustCompile(`^arn:[^:]+:iam::[0-9]{12}:policy/.+$`)
+	sessionNameRegexp = regexp.MustCompile(`^` + sessionNamePattern + `$`)
+	externalIDRegexp  = regexp.MustCompile(`^` + externalIDPattern + `$`)
+	tagKeyRegexp      = regexp.MustCompile(`^` + tagPattern + `$`)
+)
+
+// assumeRoleAttribute wraps a configschema.Attribute so that validation code
+// and schema construction can share a single source of truth for the
+// assume-role attribute set. The attribute's name is its key in the map
+// returned by assumeRoleFullSchema.
+type assumeRoleAttribute struct {
+	schema configschema.Attribute
+}
+
+func (a assumeRoleAttribute) SchemaAttribute() *configschema.Attribute {
+	return &a.schema
+}
+
+// assumeRoleFullSchema describes the attributes supported by the nested
+// `assume_role` configuration block. It is also used to validate the
+// deprecated flat top-level assume-role attributes, once translated into
+// this shape.
+func assumeRoleFullSchema() map[string]assumeRoleAttribute {
+	return map[string]assumeRoleAttribute{
+		"role_arn": {
+			schema: configschema.Attribute{
+				Type:        cty.String,
+				Required:    true,
+				Description: "The role to be assumed.",
+			},
+		},
+		"duration": {
+			schema: configschema.Attribute{
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The duration of the session.",
+			},
+		},
+		"external_id": {
+			schema: configschema.Attribute{
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The external ID to use.",
+			},
+		},
+		"policy": {
+			schema: configschema.Attribute{
+				Type:        cty.String,
+				Optional:    true,
+				Description: "IAM policy in JSON format to scope the session down with.",
+			},
+		},
+		"policy_arns": {
+			schema: configschema.Attribute{
+				Type:        cty.Set(cty.String),
+				Optional:    true,
+				Description: "Set of Amazon Resource Names (ARNs) of IAM Policies describing further restricting permissions for the role.",
+			},
+		},
+		"session_name": {
+			schema: configschema.Attribute{
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The session name to use when assuming the role.",
+			},
+		},
+		"source_identity": {
+			schema: configschema.Attribute{
+				Type:        cty.String,
+				Optional:    true,
+				Description: "Source identity specified by the principal assuming the role.",
+			},
+		},
+		"tags": {
+			schema: configschema.Attribute{
+				Type:        cty.Map(cty.String),
+				Optional:    true,
+				Description: "Map of assume role session tags.",
+			},
+		},
+		"transitive_tag_keys": {
+			schema: configschema.Attribute{
+				Type:        cty.Set(cty.String),
+				Optional:    true,
+				Description: "Set of assume role session tag keys to pass to any subsequent sessions.",
+			},
+		},
+	}
+}
+
+// assumeRoleSchema describes the nested `assume_role` configuration block.
+// It is derived from assumeRoleFullSchema so the block and the legacy flat
+// attributes stay validated the same way.
+func assumeRoleSchema() *configschema.Block {
+	attrs := assumeRoleFullSchema()
+	block := &configschema.Block{
+		Attributes: make(map[string]*configschema.Attribute, len(attrs)),
+	}
+	for name, attr := range attrs {
+		block.Attributes[name] = attr.SchemaAttribute()
+	}
+	return block
+}
+
+// prepareAssumeRoleConfig validates a cty.Value shaped like the object
+// returned by assumeRoleFullSchema, anchoring any diagnostics at path.
+func prepareAssumeRoleConfig(obj cty.Value, path cty.Path) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	if obj.IsNull() {
+		return diags
+	}
+
+	roleArnPath := path.GetAttr("role_arn")
+	roleArnVal := obj.GetAttr("role_arn")
+	if roleArnVal.IsNull() {
+		diags = diags.Append(requiredAttributeErrDiag(roleArnPath))
+	} else if roleArn := roleArnVal.AsString(); roleArn == "" {
+		diags = diags.Append(requiredAttributeErrDiag(roleArnPath))
+	} else if _, err := arn.Parse(roleArn); err != nil {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid ARN",
+			fmt.Sprintf("The value %q cannot be parsed as an ARN: %s", roleArn, err),
+			roleArnPath,
+		))
+	} else if !roleArnRegexp.MatchString(roleArn) {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid ARN",
+			fmt.Sprintf("The value %q is not a valid IAM role ARN", roleArn),
+			roleArnPath,
+		))
+	}
+
+	if durationVal := obj.GetAttr("duration"); !durationVal.IsNull() {
+		durationPath := path.GetAttr("duration")
+		durationStr := durationVal.AsString()
+		if duration, err := time.ParseDuration(durationStr); err != nil {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Duration",
+				fmt.Sprintf("The value %q cannot be parsed as a duration: %s", durationStr, err),
+				durationPath,
+			))
+		} else if duration < assumeRoleMinDuration || duration > assumeRoleMaxDuration {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Duration",
+				fmt.Sprintf("Duration must be between %s and %s, had %s", assumeRoleMinDuration, assumeRoleMaxDuration, duration),
+				durationPath,
+			))
+		}
+	}
+
+	if externalIDVal := obj.GetAttr("external_id"); !externalIDVal.IsNull() {
+		externalIDPath := path.GetAttr("external_id")
+		externalID := externalIDVal.AsString()
+		if l := len(externalID); l < 2 || l > 1224 {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value Length",
+				fmt.Sprintf("Length must be between 2 and 1224, had %d", l),
+				externalIDPath,
+			))
+		} else if !externalIDRegexp.MatchString(externalID) {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf(`Length must be between 2 and 1224 and satisfy the pattern %s, had %q`, externalIDPattern, externalID),
+				externalIDPath,
+			))
+		}
+	}
+
+	if sessionNameVal := obj.GetAttr("session_name"); !sessionNameVal.IsNull() {
+		sessionNamePath := path.GetAttr("session_name")
+		sessionName := sessionNameVal.AsString()
+		if l := len(sessionName); l < 2 || l > 64 {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value Length",
+				fmt.Sprintf("Length must be between 2 and 64, had %d", l),
+				sessionNamePath,
+			))
+		} else if !sessionNameRegexp.MatchString(sessionName) {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf(`Length must be between 2 and 64 and satisfy the pattern %s, had %q`, sessionNamePattern, sessionName),
+				sessionNamePath,
+			))
+		}
+	}
+
+	if policyVal := obj.GetAttr("policy"); !policyVal.IsNull() {
+		policyPath := path.GetAttr("policy")
+		policy := policyVal.AsString()
+		if strings.TrimSpace(policy) == "" {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				"The value cannot be empty or all whitespace",
+				policyPath,
+			))
+		} else if err := json.Unmarshal([]byte(policy), new(interface{})); err != nil {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf("The value must be valid JSON: %s", err),
+				policyPath,
+			))
+		}
+	}
+
+	if policyArnsVal := obj.GetAttr("policy_arns"); !policyArnsVal.IsNull() {
+		policyArnsPath := path.GetAttr("policy_arns")
+		policyArnsVal.ForEachElement(func(_, v cty.Value) bool {
+			value := v.AsString()
+			if _, err := arn.Parse(value); err != nil {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid ARN",
+					fmt.Sprintf("The value %q cannot be parsed as an ARN: %s", value, err),
+					policyArnsPath.IndexString(value),
+				))
+			} else if !policyArnRegexp.MatchString(value) {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid ARN",
+					fmt.Sprintf("The value %q is not a valid IAM policy ARN", value),
+					policyArnsPath.IndexString(value),
+				))
+			}
+			return false
+		})
+	}
+
+	tagsVal := obj.GetAttr("tags")
+	tagKeys := make(map[string]struct{})
+	if !tagsVal.IsNull() {
+		tagsPath := path.GetAttr("tags")
+		tagsVal.ForEachElement(func(k, v cty.Value) bool {
+			key := k.AsString()
+			tagKeys[key] = struct{}{}
+
+			if l := len(key); l < 1 || l > 128 || !tagKeyRegexp.MatchString(key) {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid Value",
+					fmt.Sprintf(`The tag key %q must be between 1 and 128 characters and satisfy the pattern %s, had %q`, key, tagPattern, key),
+					tagsPath.IndexString(key),
+				))
+			}
+
+			value := v.AsString()
+			if l := len(value); l > 256 || (l > 0 && !tagKeyRegexp.MatchString(value)) {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid Value",
+					fmt.Sprintf(`The tag value %q for key %q must be at most 256 characters and satisfy the pattern %s`, value, key, tagPattern),
+					tagsPath.IndexString(key),
+				))
+			}
+
+			return false
+		})
+	}
+
+	if transitiveTagKeysVal := obj.GetAttr("transitive_tag_keys"); !transitiveTagKeysVal.IsNull() {
+		transitiveTagKeysPath := path.GetAttr("transitive_tag_keys")
+		transitiveTagKeysVal.ForEachElement(func(_, v cty.Value) bool {
+			key := v.AsString()
+			if _, ok := tagKeys[key]; !ok {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid Value",
+					fmt.Sprintf("The key %q must also be present in \"tags\"", key),
+					transitiveTagKeysPath.IndexString(key),
+				))
+			}
+			return false
+		})
+	}
+
+	return diags
+}
+
+// prepareFlatAssumeRoleConfig validates the deprecated flat top-level
+// assume-role attributes, applying the same rules as prepareAssumeRoleConfig
+// but anchoring diagnostics at the legacy attribute paths.
+func prepareFlatAssumeRoleConfig(obj cty.Value) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	roleArnPath := cty.GetAttrPath("role_arn")
+	roleArn := obj.GetAttr("role_arn").AsString()
+	if _, err := arn.Parse(roleArn); err != nil {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid ARN",
+			fmt.Sprintf("The value %q cannot be parsed as an ARN: %s", roleArn, err),
+			roleArnPath,
+		))
+	} else if !roleArnRegexp.MatchString(roleArn) {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid ARN",
+			fmt.Sprintf("The value %q is not a valid IAM role ARN", roleArn),
+			roleArnPath,
+		))
+	}
+
+	if durationVal := obj.GetAttr("assume_role_duration_seconds"); !durationVal.IsNull() {
+		durationPath := cty.GetAttrPath("assume_role_duration_seconds")
+		var seconds int
+		if err := gocty.FromCtyValue(durationVal, &seconds); err == nil {
+			duration := time.Duration(seconds) * time.Second
+			if duration < assumeRoleMinDuration || duration > assumeRoleMaxDuration {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid Duration",
+					fmt.Sprintf("Duration must be between %s and %s, had %s", assumeRoleMinDuration, assumeRoleMaxDuration, duration),
+					durationPath,
+				))
+			}
+		}
+	}
+
+	if externalIDVal := obj.GetAttr("external_id"); !externalIDVal.IsNull() && externalIDVal.AsString() != "" {
+		externalIDPath := cty.GetAttrPath("external_id")
+		externalID := externalIDVal.AsString()
+		if l := len(externalID); l < 2 || l > 1224 {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value Length",
+				fmt.Sprintf("Length must be between 2 and 1224, had %d", l),
+				externalIDPath,
+			))
+		} else if !externalIDRegexp.MatchString(externalID) {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf(`Length must be between 2 and 1224 and satisfy the pattern %s, had %q`, externalIDPattern, externalID),
+				externalIDPath,
+			))
+		}
+	}
+
+	if sessionNameVal := obj.GetAttr("session_name"); !sessionNameVal.IsNull() && sessionNameVal.AsString() != "" {
+		sessionNamePath := cty.GetAttrPath("session_name")
+		sessionName := sessionNameVal.AsString()
+		if l := len(sessionName); l < 2 || l > 64 {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value Length",
+				fmt.Sprintf("Length must be between 2 and 64, had %d", l),
+				sessionNamePath,
+			))
+		} else if !sessionNameRegexp.MatchString(sessionName) {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf(`Length must be between 2 and 64 and satisfy the pattern %s, had %q`, sessionNamePattern, sessionName),
+				sessionNamePath,
+			))
+		}
+	}
+
+	if policyVal := obj.GetAttr("assume_role_policy"); !policyVal.IsNull() {
+		policyPath := cty.GetAttrPath("assume_role_policy")
+		policy := policyVal.AsString()
+		if strings.TrimSpace(policy) == "" {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				"The value cannot be empty or all whitespace",
+				policyPath,
+			))
+		} else if err := json.Unmarshal([]byte(policy), new(interface{})); err != nil {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf("The value must be valid JSON: %s", err),
+				policyPath,
+			))
+		}
+	}
+
+	if policyArnsVal := obj.GetAttr("assume_role_policy_arns"); !policyArnsVal.IsNull() {
+		policyArnsPath := cty.GetAttrPath("assume_role_policy_arns")
+		policyArnsVal.ForEachElement(func(_, v cty.Value) bool {
+			value := v.AsString()
+			if _, err := arn.Parse(value); err != nil {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid ARN",
+					fmt.Sprintf("The value %q cannot be parsed as an ARN: %s", value, err),
+					policyArnsPath.IndexString(value),
+				))
+			} else if !policyArnRegexp.MatchString(value) {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid ARN",
+					fmt.Sprintf("The value %q is not a valid IAM policy ARN", value),
+					policyArnsPath.IndexString(value),
+				))
+			}
+			return false
+		})
+	}
+
+	tagsVal := obj.GetAttr("assume_role_tags")
+	tagKeys := make(map[string]struct{})
+	if !tagsVal.IsNull() {
+		tagsPath := cty.GetAttrPath("assume_role_tags")
+		tagsVal.ForEachElement(func(k, v cty.Value) bool {
+			key := k.AsString()
+			tagKeys[key] = struct{}{}
+
+			if l := len(key); l < 1 || l > 128 || !tagKeyRegexp.MatchString(key) {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid Value",
+					fmt.Sprintf(`The tag key %q must be between 1 and 128 characters and satisfy the pattern %s, had %q`, key, tagPattern, key),
+					tagsPath.IndexString(key),
+				))
+			}
+
+			value := v.AsString()
+			if l := len(value); l > 256 || (l > 0 && !tagKeyRegexp.MatchString(value)) {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid Value",
+					fmt.Sprintf(`The tag value %q for key %q must be at most 256 characters and satisfy the pattern %s`, value, key, tagPattern),
+					tagsPath.IndexString(key),
+				))
+			}
+
+			return false
+		})
+	}
+
+	if transitiveTagKeysVal := obj.GetAttr("assume_role_transitive_tag_keys"); !transitiveTagKeysVal.IsNull() {
+		transitiveTagKeysPath := cty.GetAttrPath("assume_role_transitive_tag_keys")
+		transitiveTagKeysVal.ForEachElement(func(_, v cty.Value) bool {
+			key := v.AsString()
+			if _, ok := tagKeys[key]; !ok {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid Value",
+					fmt.Sprintf("The key %q must also be present in \"assume_role_tags\"", key),
+					transitiveTagKeysPath.IndexString(key),
+				))
+			}
+			return false
+		})
+	}
+
+	return diags
+}
+
+// newAssumeRole translates a validated assume_role block (shaped like
+// assumeRoleFullSchema) into the credentials configuration consumed by
+// aws-sdk-go-base.
+func newAssumeRole(obj cty.Value) (*awsbase.AssumeRole, error) {
+	r := &awsbase.AssumeRole{
+		RoleARN: obj.GetAttr("role_arn").AsString(),
+	}
+
+	if val := obj.GetAttr("duration"); !val.IsNull() {
+		duration, err := time.ParseDuration(val.AsString())
+		if err != nil {
+			return nil, fmt.Errorf("parsing duration: %w", err)
+		}
+		r.Duration = duration
+	} else {
+		r.Duration = 15 * time.Minute
+	}
+	if val := obj.GetAttr("external_id"); !val.IsNull() {
+		r.ExternalID = val.AsString()
+	}
+	if val := obj.GetAttr("policy"); !val.IsNull() {
+		r.Policy = val.AsString()
+	}
+	if val := obj.GetAttr("policy_arns"); !val.IsNull() {
+		val.ForEachElement(func(_, v cty.Value) bool {
+			r.PolicyARNs = append(r.PolicyARNs, v.AsString())
+			return false
+		})
+	}
+	if val := obj.GetAttr("session_name"); !val.IsNull() {
+		r.SessionName = val.AsString()
+	}
+	if val := obj.GetAttr("source_identity"); !val.IsNull() {
+		r.SourceIdentity = val.AsString()
+	}
+	if val := obj.GetAttr("tags"); !val.IsNull() {
+		r.Tags = make(map[string]string)
+		val.ForEachElement(func(k, v cty.Value) bool {
+			r.Tags[k.AsString()] = v.AsString()
+			return false
+		})
+	}
+	if val := obj.GetAttr("transitive_tag_keys"); !val.IsNull() {
+		val.ForEachElement(func(_, v cty.Value) bool {
+			r.TransitiveTagKeys = append(r.TransitiveTagKeys, v.AsString())
+			return false
+		})
+	}
+
+	return r, nil
+}
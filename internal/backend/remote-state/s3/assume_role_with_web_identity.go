@@ -0,0 +1,182 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	awsbase "github.com/hashicorp/aws-sdk-go-base/v2"
+	"github.com/hashicorp/terraform/internal/configs/configschema"
+	"github.com/hashicorp/terraform/internal/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// assumeRoleWithWebIdentitySchema describes the nested
+// `assume_role_with_web_identity` configuration block, which authenticates
+// using an OIDC/JWT token (for example from GitHub Actions, EKS IRSA, or
+// GitLab CI) instead of long-lived AWS credentials.
+func assumeRoleWithWebIdentitySchema() *configschema.Block {
+	return &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"role_arn": {
+				Type:        cty.String,
+				Required:    true,
+				Description: "The role to be assumed.",
+			},
+			"session_name": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The session name to use when assuming the role.",
+			},
+			"policy": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "IAM policy in JSON format to scope the session down with.",
+			},
+			"policy_arns": {
+				Type:        cty.Set(cty.String),
+				Optional:    true,
+				Description: "Set of Amazon Resource Names (ARNs) of IAM Policies describing further restricting permissions for the role.",
+			},
+			"duration": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The duration of the session.",
+			},
+			"web_identity_token": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The OAuth 2.0 access token or OpenID Connect ID token provided by the identity provider.",
+			},
+			"web_identity_token_file": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "A file containing an OAuth 2.0 access token or OpenID Connect ID token provided by the identity provider.",
+			},
+		},
+	}
+}
+
+// prepareAssumeRoleWithWebIdentityConfig validates the
+// assume_role_with_web_identity block, anchoring diagnostics at path.
+func prepareAssumeRoleWithWebIdentityConfig(obj cty.Value, path cty.Path) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	if obj.IsNull() {
+		return diags
+	}
+
+	roleArnPath := path.GetAttr("role_arn")
+	roleArnVal := obj.GetAttr("role_arn")
+	if roleArnVal.IsNull() || roleArnVal.AsString() == "" {
+		diags = diags.Append(requiredAttributeErrDiag(roleArnPath))
+	} else {
+		roleArn := roleArnVal.AsString()
+		if _, err := arn.Parse(roleArn); err != nil {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid ARN",
+				fmt.Sprintf("The value %q cannot be parsed as an ARN: %s", roleArn, err),
+				roleArnPath,
+			))
+		}
+	}
+
+	tokenVal := obj.GetAttr("web_identity_token")
+	tokenFileVal := obj.GetAttr("web_identity_token_file")
+	hasToken := !tokenVal.IsNull() && tokenVal.AsString() != ""
+	hasTokenFile := !tokenFileVal.IsNull() && tokenFileVal.AsString() != ""
+
+	switch {
+	case hasToken && hasTokenFile:
+		diags = diags.Append(attributeErrDiag(
+			"Invalid Attribute Combination",
+			`Only one of "web_identity_token" or "web_identity_token_file" can be set.`,
+			path,
+		))
+	case !hasToken && !hasTokenFile:
+		diags = diags.Append(attributeErrDiag(
+			"Missing Required Value",
+			`One of "web_identity_token" or "web_identity_token_file" must be set.`,
+			path,
+		))
+	}
+
+	if durationVal := obj.GetAttr("duration"); !durationVal.IsNull() {
+		durationPath := path.GetAttr("duration")
+		duration := durationVal.AsString()
+		if _, err := time.ParseDuration(duration); err != nil {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Duration",
+				fmt.Sprintf("The value %q cannot be parsed as a duration: %s", duration, err),
+				durationPath,
+			))
+		}
+	}
+
+	if policyVal := obj.GetAttr("policy"); !policyVal.IsNull() && policyVal.AsString() == "" {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid Value",
+			"The value cannot be empty or all whitespace",
+			path.GetAttr("policy"),
+		))
+	}
+
+	if policyArnsVal := obj.GetAttr("policy_arns"); !policyArnsVal.IsNull() {
+		policyArnsPath := path.GetAttr("policy_arns")
+		policyArnsVal.ForEachElement(func(_, v cty.Value) bool {
+			value := v.AsString()
+			if _, err := arn.Parse(value); err != nil {
+				diags = diags.Append(attributeErrDiag(
+					"Invalid ARN",
+					fmt.Sprintf("The value %q cannot be parsed as an ARN: %s", value, err),
+					policyArnsPath.IndexString(value),
+				))
+			}
+			return false
+		})
+	}
+
+	return diags
+}
+
+// newAssumeRoleWithWebIdentity translates a validated
+// assume_role_with_web_identity block into the credentials configuration
+// consumed by aws-sdk-go-base.
+func newAssumeRoleWithWebIdentity(obj cty.Value) (*awsbase.AssumeRoleWithWebIdentity, error) {
+	w := &awsbase.AssumeRoleWithWebIdentity{
+		RoleARN: obj.GetAttr("role_arn").AsString(),
+	}
+
+	if val := obj.GetAttr("session_name"); !val.IsNull() {
+		w.SessionName = val.AsString()
+	}
+	if val := obj.GetAttr("policy"); !val.IsNull() {
+		w.Policy = val.AsString()
+	}
+	if val := obj.GetAttr("policy_arns"); !val.IsNull() {
+		val.ForEachElement(func(_, v cty.Value) bool {
+			w.PolicyARNs = append(w.PolicyARNs, v.AsString())
+			return false
+		})
+	}
+	if val := obj.GetAttr("duration"); !val.IsNull() {
+		duration, err := time.ParseDuration(val.AsString())
+		if err != nil {
+			return nil, fmt.Errorf("parsing duration: %w", err)
+		}
+		w.Duration = duration
+	} else {
+		w.Duration = 15 * time.Minute
+	}
+	if val := obj.GetAttr("web_identity_token"); !val.IsNull() {
+		w.WebIdentityToken = val.AsString()
+	}
+	if val := obj.GetAttr("web_identity_token_file"); !val.IsNull() {
+		w.WebIdentityTokenFile = val.AsString()
+	}
+
+	return w, nil
+}
@@ -0,0 +1,634 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	awsbase "github.com/hashicorp/aws-sdk-go-base/v2"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/hashicorp/terraform/internal/backend"
+	"github.com/hashicorp/terraform/internal/configs/configschema"
+	"github.com/hashicorp/terraform/internal/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// New creates a new backend for S3 remote state.
+func New() backend.Backend {
+	return &Backend{}
+}
+
+// Backend implements "backend".Backend for an S3 compatible object store,
+// optionally using a separate store (DynamoDB, or S3 itself) for state
+// locking.
+type Backend struct {
+	s3Client  *s3.Client
+	dynClient *dynamodb.Client
+
+	awsConfig aws.Config
+
+	bucketName            string
+	keyName               string
+	serverSideEncryption  bool
+	customerEncryptionKey []byte
+	acl                   string
+	kmsKeyID              string
+	ddbTable              string
+	lockBackend           string
+	workspaceKeyPrefix    string
+
+	vaultCredentials *vaultAWSCredentialsProvider
+}
+
+// The supported values of the lock_backend attribute.
+const (
+	lockBackendDynamoDB = "dynamodb"
+	lockBackendS3       = "s3"
+	lockBackendNone     = "none"
+)
+
+// Close stops any background goroutines owned by the backend, such as the
+// Vault lease renewal loop started by Configure when the
+// vault_aws_credentials block is in use. It is safe to call on a Backend
+// that was never configured, or more than once.
+func (b *Backend) Close() error {
+	if b.vaultCredentials != nil {
+		return b.vaultCredentials.Close()
+	}
+	return nil
+}
+
+func (b *Backend) ConfigSchema() *configschema.Block {
+	return &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"bucket": {
+				Type:        cty.String,
+				Required:    true,
+				Description: "The name of the S3 bucket.",
+			},
+			"key": {
+				Type:        cty.String,
+				Required:    true,
+				Description: "The path to the state file inside the bucket.",
+			},
+			"region": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The region of the AWS provider.",
+			},
+			"encrypt": {
+				Type:        cty.Bool,
+				Optional:    true,
+				Description: "Whether to enable server side encryption of the state file.",
+			},
+			"acl": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "Canned ACL to be applied to the state file.",
+			},
+			"workspace_key_prefix": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The prefix applied to the non-default state path inside the bucket.",
+			},
+			"kms_key_id": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The ARN of a KMS Key to use for encrypting the state.",
+			},
+			"sse_customer_key": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The base64-encoded encryption key to use for server side encryption with customer-provided keys (SSE-C).",
+			},
+			"dynamodb_table": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "DynamoDB table for state locking and consistency.",
+			},
+			"lock_backend": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: `The backend to use for state locking: "dynamodb", "s3", or "none". Defaults to "dynamodb" when "dynamodb_table" is set, otherwise "s3".`,
+			},
+			"skip_credentials_validation": {
+				Type:        cty.Bool,
+				Optional:    true,
+				Description: "Skip the credentials validation via STS API.",
+			},
+			"skip_region_validation": {
+				Type:        cty.Bool,
+				Optional:    true,
+				Description: "Skip static validation of region name.",
+			},
+			"sts_endpoint": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "A custom endpoint for the STS API.",
+			},
+
+			"access_key": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "AWS access key.",
+			},
+			"secret_key": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "AWS secret key.",
+			},
+			"token": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "AWS session token.",
+			},
+			"profile": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The AWS profile to use.",
+			},
+
+			// Deprecated flat assume-role attributes. Prefer the nested
+			// `assume_role` block.
+			"role_arn": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The role to be assumed.",
+			},
+			"session_name": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The session name to use when assuming the role.",
+			},
+			"external_id": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The external ID to use when assuming the role.",
+			},
+			"assume_role_duration_seconds": {
+				Type:        cty.Number,
+				Optional:    true,
+				Description: "The duration, in seconds, of the role session.",
+			},
+			"assume_role_policy": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "IAM policy in JSON format to scope the session down with.",
+			},
+			"assume_role_policy_arns": {
+				Type:        cty.Set(cty.String),
+				Optional:    true,
+				Description: "Set of Amazon Resource Names (ARNs) of IAM Policies describing further restricting permissions for the IAM Role being assumed.",
+			},
+			"assume_role_tags": {
+				Type:        cty.Map(cty.String),
+				Optional:    true,
+				Description: "Map of assume role session tags.",
+			},
+			"assume_role_transitive_tag_keys": {
+				Type:        cty.Set(cty.String),
+				Optional:    true,
+				Description: "Set of assume role session tag keys to pass to any subsequent sessions.",
+			},
+		},
+
+		BlockTypes: map[string]*configschema.NestedBlock{
+			"assume_role": {
+				Nesting: configschema.NestingSingle,
+				Block:   assumeRoleSchema(),
+			},
+			"assume_role_with_web_identity": {
+				Nesting: configschema.NestingSingle,
+				Block:   assumeRoleWithWebIdentitySchema(),
+			},
+			"vault_aws_credentials": {
+				Nesting: configschema.NestingSingle,
+				Block:   vaultAWSCredentialsSchema(),
+			},
+		},
+	}
+}
+
+func (b *Backend) PrepareConfig(obj cty.Value) (cty.Value, tfdiags.Diagnostics) {
+	var diags tfdiags.Diagnostics
+	if obj.IsNull() {
+		return obj, diags
+	}
+
+	if val := obj.GetAttr("bucket"); val.IsNull() {
+		diags = diags.Append(requiredAttributeErrDiag(cty.GetAttrPath("bucket")))
+	} else if strings.TrimSpace(val.AsString()) == "" {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid Value",
+			"The value cannot be empty or all whitespace",
+			cty.GetAttrPath("bucket"),
+		))
+	}
+
+	if val := obj.GetAttr("key"); val.IsNull() {
+		diags = diags.Append(requiredAttributeErrDiag(cty.GetAttrPath("key")))
+	} else if key := val.AsString(); strings.TrimSpace(key) == "" {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid Value",
+			"The value cannot be empty or all whitespace",
+			cty.GetAttrPath("key"),
+		))
+	} else if strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid Value",
+			`The value must not start or end with "/"`,
+			cty.GetAttrPath("key"),
+		))
+	}
+
+	if val := obj.GetAttr("region"); val.IsNull() || strings.TrimSpace(val.AsString()) == "" {
+		if os.Getenv("AWS_REGION") == "" && os.Getenv("AWS_DEFAULT_REGION") == "" {
+			diags = diags.Append(attributeErrDiag(
+				"Missing region value",
+				`The "region" attribute or the "AWS_REGION" or "AWS_DEFAULT_REGION" environment variables must be set.`,
+				cty.GetAttrPath("region"),
+			))
+		}
+	}
+
+	if val := obj.GetAttr("workspace_key_prefix"); !val.IsNull() {
+		if prefix := val.AsString(); strings.HasPrefix(prefix, "/") || strings.HasSuffix(prefix, "/") {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				`The value must not start or end with "/"`,
+				cty.GetAttrPath("workspace_key_prefix"),
+			))
+		}
+	}
+
+	var customerKeySet, kmsKeySet bool
+	if val := obj.GetAttr("sse_customer_key"); !val.IsNull() && val.AsString() != "" {
+		customerKeySet = true
+	}
+	if val := obj.GetAttr("kms_key_id"); !val.IsNull() && val.AsString() != "" {
+		kmsKeySet = true
+	}
+	if customerKeySet && kmsKeySet {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid Attribute Combination",
+			`Only one of kms_key_id, sse_customer_key can be set.`,
+			cty.Path{},
+		))
+	}
+
+	if val := obj.GetAttr("kms_key_id"); !val.IsNull() && val.AsString() != "" {
+		if !kmsKeyIDRegexp.MatchString(val.AsString()) {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid KMS Key ID",
+				fmt.Sprintf("Value must be a valid KMS Key ID, got %q", val.AsString()),
+				cty.GetAttrPath("kms_key_id"),
+			))
+		}
+	}
+
+	if val := obj.GetAttr("lock_backend"); !val.IsNull() && val.AsString() != "" {
+		switch val.AsString() {
+		case lockBackendDynamoDB, lockBackendS3, lockBackendNone:
+		default:
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf(`The value %q must be one of "dynamodb", "s3", or "none"`, val.AsString()),
+				cty.GetAttrPath("lock_backend"),
+			))
+		}
+	}
+
+	flatRoleArn := obj.GetAttr("role_arn")
+	hasFlatRoleArn := !flatRoleArn.IsNull() && flatRoleArn.AsString() != ""
+
+	if blockVal := obj.GetAttr("assume_role"); !blockVal.IsNull() {
+		diags = diags.Append(prepareAssumeRoleConfig(blockVal, cty.GetAttrPath("assume_role")))
+
+		if hasFlatRoleArn {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Attribute Combination",
+				`Only one of "role_arn" (deprecated) or the "assume_role" block may be set.`,
+				cty.Path{},
+			))
+		}
+	} else if hasFlatRoleArn {
+		diags = diags.Append(tfdiags.Sourceless(
+			tfdiags.Warning,
+			`Argument is deprecated`,
+			`The "role_arn" argument, and the other top-level assume-role arguments, are deprecated. Use the "assume_role" block instead.`,
+		))
+		diags = diags.Append(prepareFlatAssumeRoleConfig(obj))
+	}
+
+	if blockVal := obj.GetAttr("assume_role_with_web_identity"); !blockVal.IsNull() {
+		diags = diags.Append(prepareAssumeRoleWithWebIdentityConfig(blockVal, cty.GetAttrPath("assume_role_with_web_identity")))
+
+		if hasFlatRoleArn {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Attribute Combination",
+				`Only one of "role_arn" (classic assume role) or "assume_role_with_web_identity" may be set.`,
+				cty.Path{},
+			))
+		}
+
+		if blockVal := obj.GetAttr("assume_role"); !blockVal.IsNull() {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Attribute Combination",
+				`Only one of the "assume_role" or "assume_role_with_web_identity" blocks may be set.`,
+				cty.Path{},
+			))
+		}
+	}
+
+	if blockVal := obj.GetAttr("vault_aws_credentials"); !blockVal.IsNull() {
+		diags = diags.Append(prepareVaultAWSCredentialsConfig(blockVal, cty.GetAttrPath("vault_aws_credentials")))
+
+		var hasStaticCreds bool
+		for _, name := range []string{"access_key", "secret_key", "profile"} {
+			if val := obj.GetAttr(name); !val.IsNull() && val.AsString() != "" {
+				hasStaticCreds = true
+			}
+		}
+		if hasStaticCreds {
+			diags = diags.Append(wholeBodyErrDiag(
+				"Invalid credentials configuration",
+				vaultCredentialsConflictError,
+			))
+		}
+	}
+
+	return obj, diags
+}
+
+var kmsKeyIDRegexp = regexp.MustCompile(`^arn:[^:]+:kms:[^:]+:\d{12}:key/.+$`)
+
+func (b *Backend) Configure(obj cty.Value) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	ctx := context.TODO()
+
+	b.bucketName = obj.GetAttr("bucket").AsString()
+	b.keyName = obj.GetAttr("key").AsString()
+
+	if val := obj.GetAttr("workspace_key_prefix"); !val.IsNull() {
+		b.workspaceKeyPrefix = val.AsString()
+	} else {
+		b.workspaceKeyPrefix = "env:"
+	}
+
+	if val := obj.GetAttr("encrypt"); !val.IsNull() {
+		b.serverSideEncryption = val.True()
+	}
+	if val := obj.GetAttr("acl"); !val.IsNull() {
+		b.acl = val.AsString()
+	}
+	if val := obj.GetAttr("kms_key_id"); !val.IsNull() {
+		b.kmsKeyID = val.AsString()
+	}
+	if val := obj.GetAttr("dynamodb_table"); !val.IsNull() {
+		b.ddbTable = val.AsString()
+	}
+	if val := obj.GetAttr("lock_backend"); !val.IsNull() && val.AsString() != "" {
+		b.lockBackend = val.AsString()
+	} else if b.ddbTable != "" {
+		b.lockBackend = lockBackendDynamoDB
+	} else {
+		b.lockBackend = lockBackendS3
+	}
+
+	if val := obj.GetAttr("sse_customer_key"); !val.IsNull() && val.AsString() != "" {
+		key, err := decodeCustomerEncryptionKey("sse_customer_key", val.AsString())
+		if err != nil {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid sse_customer_key value",
+				err.Error(),
+				cty.GetAttrPath("sse_customer_key"),
+			))
+		} else {
+			b.customerEncryptionKey = key
+		}
+	} else if envKey := os.Getenv("AWS_SSE_CUSTOMER_KEY"); envKey != "" {
+		if b.kmsKeyID != "" {
+			diags = diags.Append(wholeBodyErrDiag(
+				"Invalid encryption configuration",
+				encryptionKeyConflictEnvVarError,
+			))
+		} else {
+			key, err := decodeCustomerEncryptionKey("AWS_SSE_CUSTOMER_KEY", envKey)
+			if err != nil {
+				diags = diags.Append(wholeBodyErrDiag(
+					"Invalid AWS_SSE_CUSTOMER_KEY value",
+					fmt.Sprintf("The environment variable \"AWS_SSE_CUSTOMER_KEY\" %s", err),
+				))
+			} else {
+				b.customerEncryptionKey = key
+			}
+		}
+	}
+
+	if diags.HasErrors() {
+		return diags
+	}
+
+	cfg := &awsbase.Config{
+		AccessKey:               stringAttr(obj, "access_key"),
+		SecretKey:               stringAttr(obj, "secret_key"),
+		Token:                   stringAttr(obj, "token"),
+		Profile:                 stringAttr(obj, "profile"),
+		Region:                  stringAttr(obj, "region"),
+		SkipCredsValidation:     boolAttr(obj, "skip_credentials_validation"),
+		SkipRequestingAccountId: boolAttr(obj, "skip_credentials_validation"),
+		StsEndpoint:             stringAttr(obj, "sts_endpoint"),
+		SuppressDebugLog:        true,
+	}
+
+	// assumeRoles collects every assume-role configuration supplied, whether
+	// through the nested `assume_role` block or the deprecated flat
+	// attributes. Only one entry is currently permitted, but keeping this a
+	// slice leaves room for a future chained/multi-hop assume role without
+	// another representation change.
+	var assumeRoles []*awsbase.AssumeRole
+
+	if blockVal := obj.GetAttr("assume_role"); !blockVal.IsNull() {
+		assumeRole, err := newAssumeRole(blockVal)
+		if err != nil {
+			diags = diags.Append(wholeBodyErrDiag("Invalid assume_role configuration", err.Error()))
+			return diags
+		}
+		assumeRoles = append(assumeRoles, assumeRole)
+	} else if val := obj.GetAttr("role_arn"); !val.IsNull() && val.AsString() != "" {
+		assumeRole := &awsbase.AssumeRole{
+			RoleARN:           val.AsString(),
+			SessionName:       stringAttr(obj, "session_name"),
+			ExternalID:        stringAttr(obj, "external_id"),
+			Policy:            stringAttr(obj, "assume_role_policy"),
+			PolicyARNs:        stringSetAttr(obj, "assume_role_policy_arns"),
+			Tags:              stringMapAttr(obj, "assume_role_tags"),
+			TransitiveTagKeys: stringSetAttr(obj, "assume_role_transitive_tag_keys"),
+		}
+		if durationVal := obj.GetAttr("assume_role_duration_seconds"); !durationVal.IsNull() {
+			var seconds int
+			if err := gocty.FromCtyValue(durationVal, &seconds); err == nil {
+				assumeRole.Duration = time.Duration(seconds) * time.Second
+			}
+		} else {
+			assumeRole.Duration = 15 * time.Minute
+		}
+		assumeRoles = append(assumeRoles, assumeRole)
+	}
+
+	if len(assumeRoles) > 0 {
+		cfg.AssumeRole = assumeRoles[0]
+	}
+
+	if blockVal := obj.GetAttr("assume_role_with_web_identity"); !blockVal.IsNull() {
+		webIdentity, err := newAssumeRoleWithWebIdentity(blockVal)
+		if err != nil {
+			diags = diags.Append(wholeBodyErrDiag("Invalid assume_role_with_web_identity configuration", err.Error()))
+			return diags
+		}
+		cfg.AssumeRoleWithWebIdentity = webIdentity
+	}
+
+	awsConfig, awsDiags := awsbase.GetAwsConfig(ctx, cfg)
+	diags = diags.Append(tfdiagsFromAwsbase(awsDiags))
+	if diags.HasErrors() {
+		return diags
+	}
+
+	if blockVal := obj.GetAttr("vault_aws_credentials"); !blockVal.IsNull() {
+		vaultCredentials := newVaultAWSCredentialsProvider(blockVal)
+		vaultCredentials.startRenewLoop()
+		runtime.SetFinalizer(vaultCredentials, func(p *vaultAWSCredentialsProvider) { p.Close() })
+
+		b.vaultCredentials = vaultCredentials
+		awsConfig.Credentials = aws.NewCredentialsCache(vaultCredentials)
+	}
+
+	b.awsConfig = awsConfig
+
+	if !cfg.SkipCredsValidation {
+		stsClient := sts.NewFromConfig(awsConfig)
+		if _, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}); err != nil {
+			diags = diags.Append(tfdiags.Sourceless(
+				tfdiags.Error,
+				"Unable to get caller identity",
+				fmt.Sprintf("STS GetCallerIdentity failed: %s", err),
+			))
+			return diags
+		}
+	}
+
+	b.s3Client = s3.NewFromConfig(awsConfig)
+	b.dynClient = dynamodb.NewFromConfig(awsConfig)
+
+	return diags
+}
+
+func stringAttr(obj cty.Value, name string) string {
+	val := obj.GetAttr(name)
+	if val.IsNull() {
+		return ""
+	}
+	return val.AsString()
+}
+
+func boolAttr(obj cty.Value, name string) bool {
+	val := obj.GetAttr(name)
+	if val.IsNull() {
+		return false
+	}
+	return val.True()
+}
+
+func stringSetAttr(obj cty.Value, name string) []string {
+	val := obj.GetAttr(name)
+	if val.IsNull() {
+		return nil
+	}
+	var out []string
+	val.ForEachElement(func(_, v cty.Value) bool {
+		out = append(out, v.AsString())
+		return false
+	})
+	return out
+}
+
+func stringMapAttr(obj cty.Value, name string) map[string]string {
+	val := obj.GetAttr(name)
+	if val.IsNull() {
+		return nil
+	}
+	out := make(map[string]string)
+	val.ForEachElement(func(k, v cty.Value) bool {
+		out[k.AsString()] = v.AsString()
+		return false
+	})
+	return out
+}
+
+func tfdiagsFromAwsbase(awsDiags awsbase.Diagnostics) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+	for _, d := range awsDiags {
+		sev := tfdiags.Error
+		if d.Severity() == awsbase.SeverityWarning {
+			sev = tfdiags.Warning
+		}
+		diags = diags.Append(tfdiags.Sourceless(sev, d.Summary(), d.Detail()))
+	}
+	return diags
+}
+
+// keyEnv returns the workspace name associated with the given state object
+// key, or "" if the key belongs to the default workspace.
+func (b *Backend) keyEnv(key string) string {
+	prefix := b.workspaceKeyPrefix
+	if prefix == "" {
+		prefix = "env:"
+	}
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	if !strings.HasPrefix(key, prefix) {
+		return ""
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 2)
+	if len(parts) < 2 || parts[1] != b.keyName {
+		return ""
+	}
+	return parts[0]
+}
+
+// path returns the object key for the given workspace name.
+func (b *Backend) path(name string) string {
+	if name == backend.DefaultStateName || name == "" {
+		return b.keyName
+	}
+	prefix := b.workspaceKeyPrefix
+	if prefix == "" {
+		prefix = "env:"
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + name + "/" + b.keyName
+}
+
+func decodeCustomerEncryptionKey(attr, value string) ([]byte, error) {
+	if len(value) != 44 {
+		return nil, fmt.Errorf("%s must be 44 characters in length", attr)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be base64 encoded: %s", attr, err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/hashicorp/terraform/internal/states/remote"
+	"github.com/hashicorp/terraform/internal/states/statemgr"
+)
+
+// RemoteClient implements remote.Client and remote.ClientLocker for state
+// stored in an S3 bucket, optionally guarded by a Locker.
+type RemoteClient struct {
+	s3Client *s3.Client
+
+	bucketName            string
+	path                  string
+	serverSideEncryption  bool
+	customerEncryptionKey []byte
+	acl                   string
+	kmsKeyID              string
+
+	// locker is nil when state locking is disabled (lock_backend = "none").
+	locker Locker
+}
+
+var _ remote.Client = (*RemoteClient)(nil)
+var _ remote.ClientLocker = (*RemoteClient)(nil)
+
+func (c *RemoteClient) Get() (*remote.Payload, error) {
+	ctx := context.TODO()
+
+	output, err := c.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:               aws.String(c.bucketName),
+		Key:                  aws.String(c.path),
+		SSECustomerAlgorithm: c.sseCustomerAlgorithm(),
+		SSECustomerKey:       c.sseCustomerKey(),
+	})
+	if err != nil {
+		var nsk *s3types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	return &remote.Payload{
+		Data: data,
+	}, nil
+}
+
+func (c *RemoteClient) Put(data []byte) error {
+	ctx := context.TODO()
+
+	input := &s3.PutObjectInput{
+		Bucket:               aws.String(c.bucketName),
+		Key:                  aws.String(c.path),
+		Body:                 bytes.NewReader(data),
+		SSECustomerAlgorithm: c.sseCustomerAlgorithm(),
+		SSECustomerKey:       c.sseCustomerKey(),
+	}
+
+	if c.serverSideEncryption && c.customerEncryptionKey == nil {
+		if c.kmsKeyID != "" {
+			input.ServerSideEncryption = s3types.ServerSideEncryptionAwsKms
+			input.SSEKMSKeyId = aws.String(c.kmsKeyID)
+		} else {
+			input.ServerSideEncryption = s3types.ServerSideEncryptionAes256
+		}
+	}
+
+	if c.acl != "" {
+		input.ACL = s3types.ObjectCannedACL(c.acl)
+	}
+
+	if _, err := c.s3Client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to put state: %w", err)
+	}
+
+	return nil
+}
+
+func (c *RemoteClient) Delete() error {
+	ctx := context.TODO()
+
+	if _, err := c.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(c.bucketName),
+		Key:    aws.String(c.path),
+	}); err != nil {
+		return fmt.Errorf("failed to delete state: %w", err)
+	}
+
+	return nil
+}
+
+func (c *RemoteClient) Lock(info *statemgr.LockInfo) (string, error) {
+	if c.locker == nil {
+		return "", nil
+	}
+	return c.locker.Lock(info)
+}
+
+func (c *RemoteClient) Unlock(id string) error {
+	if c.locker == nil {
+		return nil
+	}
+	return c.locker.Unlock(id)
+}
+
+func (c *RemoteClient) sseCustomerAlgorithm() *string {
+	if c.customerEncryptionKey == nil {
+		return nil
+	}
+	return aws.String("AES256")
+}
+
+func (c *RemoteClient) sseCustomerKey() *string {
+	if c.customerEncryptionKey == nil {
+		return nil
+	}
+	return aws.String(string(c.customerEncryptionKey))
+}
@@ -0,0 +1,257 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithy "github.com/aws/smithy-go"
+
+	"github.com/hashicorp/terraform/internal/states/statemgr"
+)
+
+// Locker is the interface RemoteClient uses to guard concurrent access to a
+// single state file. Terraform ships two implementations, dynamodbLocker and
+// s3ConditionalLocker, but the interface is exported so that third parties
+// can plug in their own backing store (Consul, Redis, etcd, ...) without
+// modifying Backend.
+type Locker interface {
+	// Lock acquires the lock described by info and returns its ID. If the
+	// lock is already held, Lock returns a *statemgr.LockError whose Info
+	// field describes the current holder when that information is
+	// available.
+	Lock(info *statemgr.LockInfo) (string, error)
+
+	// Unlock releases the lock previously acquired with the given ID.
+	Unlock(id string) error
+
+	// GetLockInfo returns the info describing whichever lock is currently
+	// held, if any.
+	GetLockInfo() (*statemgr.LockInfo, error)
+}
+
+// dynamodbLocker implements Locker using a DynamoDB table, storing one item
+// per state file keyed by LockID.
+type dynamodbLocker struct {
+	client   *dynamodb.Client
+	table    string
+	lockPath string
+}
+
+var _ Locker = (*dynamodbLocker)(nil)
+
+func (l *dynamodbLocker) Lock(info *statemgr.LockInfo) (string, error) {
+	ctx := context.TODO()
+
+	infoJSON, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+
+	item := map[string]dynamodbtypes.AttributeValue{
+		"LockID": &dynamodbtypes.AttributeValueMemberS{Value: l.lockPath},
+		"Info":   &dynamodbtypes.AttributeValueMemberS{Value: string(infoJSON)},
+	}
+
+	_, err = l.client.PutItem(ctx, &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String(l.table),
+		ConditionExpression: aws.String("attribute_not_exists(LockID)"),
+	})
+	if err != nil {
+		lockErr := &statemgr.LockError{
+			Err: fmt.Errorf("failed to acquire state lock: %w", err),
+		}
+		if existing, infoErr := l.GetLockInfo(); infoErr == nil {
+			lockErr.Info = existing
+		}
+		return "", lockErr
+	}
+
+	return info.ID, nil
+}
+
+func (l *dynamodbLocker) Unlock(id string) error {
+	ctx := context.TODO()
+
+	_, err := l.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(l.table),
+		Key: map[string]dynamodbtypes.AttributeValue{
+			"LockID": &dynamodbtypes.AttributeValueMemberS{Value: l.lockPath},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release state lock: %w", err)
+	}
+
+	return nil
+}
+
+func (l *dynamodbLocker) GetLockInfo() (*statemgr.LockInfo, error) {
+	ctx := context.TODO()
+
+	resp, err := l.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(l.table),
+		Key: map[string]dynamodbtypes.AttributeValue{
+			"LockID": &dynamodbtypes.AttributeValueMemberS{Value: l.lockPath},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	v, ok := resp.Item["Info"].(*dynamodbtypes.AttributeValueMemberS)
+	if !ok {
+		return nil, fmt.Errorf("no lock info found at %s", l.lockPath)
+	}
+
+	info := &statemgr.LockInfo{}
+	if err := json.Unmarshal([]byte(v.Value), info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// s3ConditionalLocker implements Locker directly on top of S3, for
+// S3-compatible stores (MinIO, Ceph, Cloudflare R2, ...) where a DynamoDB
+// table isn't available. It writes a lock object using a conditional
+// PutObject (If-None-Match: "*"), which fails with a PreconditionFailed
+// error if a holder already exists, and releases the lock with a
+// conditional DeleteObject (If-Match: <etag>) guarded by the ETag returned
+// at lock time, so it can't steal a lock that was released and re-acquired
+// in between.
+type s3ConditionalLocker struct {
+	client *s3.Client
+	bucket string
+
+	// lockKey is the object key of the lock file, conventionally the state
+	// path with a ".tflock" suffix.
+	lockKey string
+
+	// etag is the ETag of the lock object this instance most recently
+	// created, used to guard Unlock's DeleteObject.
+	etag string
+}
+
+var _ Locker = (*s3ConditionalLocker)(nil)
+
+// lockKeyForPath returns the lock object key for the given state path.
+func lockKeyForPath(path string) string {
+	return path + ".tflock"
+}
+
+// isConditionalRequestUnsupported reports whether err indicates that the
+// endpoint rejected the If-None-Match precondition outright, rather than
+// evaluating it and finding the lock object already present. Some older
+// S3-compatible implementations (pre-2024 MinIO, Ceph RGW, ...) predate
+// conditional writes and respond this way.
+//
+// There is no atomic way to emulate "create if absent" on such an endpoint:
+// a CopyObject-based or HEAD-then-PUT fallback would reintroduce the very
+// race this locker exists to close. So rather than silently downgrading
+// locking safety, Lock surfaces this case as a clear, actionable error.
+func isConditionalRequestUnsupported(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "NotImplemented", "MethodNotAllowed":
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *s3ConditionalLocker) Lock(info *statemgr.LockInfo) (string, error) {
+	ctx := context.TODO()
+
+	infoJSON, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal lock info: %w", err)
+	}
+
+	output, err := l.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(l.bucket),
+		Key:         aws.String(l.lockKey),
+		Body:        bytes.NewReader(infoJSON),
+		IfNoneMatch: aws.String("*"),
+	})
+	if err != nil {
+		if isConditionalRequestUnsupported(err) {
+			return "", fmt.Errorf("failed to acquire state lock: the S3-compatible endpoint rejected the If-None-Match precondition required by lock_backend = \"s3\" (older S3-compatible stores may not support conditional writes); use lock_backend = \"dynamodb\" instead: %w", err)
+		}
+		lockErr := &statemgr.LockError{
+			Err: fmt.Errorf("failed to acquire state lock: %w", err),
+		}
+		if existing, infoErr := l.GetLockInfo(); infoErr == nil {
+			lockErr.Info = existing
+		}
+		return "", lockErr
+	}
+
+	if output.ETag != nil {
+		l.etag = *output.ETag
+	}
+
+	return info.ID, nil
+}
+
+func (l *s3ConditionalLocker) Unlock(id string) error {
+	ctx := context.TODO()
+
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(l.bucket),
+		Key:    aws.String(l.lockKey),
+	}
+	if l.etag != "" {
+		// DeleteObjectInput.IfMatch requires aws-sdk-go-v2/service/s3 >=
+		// v1.58.0, which shipped alongside the PutObject If-None-Match
+		// support Lock depends on above; both are pinned together in
+		// go.mod.
+		input.IfMatch = aws.String(l.etag)
+	}
+
+	if _, err := l.client.DeleteObject(ctx, input); err != nil {
+		return fmt.Errorf("failed to release state lock: %w", err)
+	}
+
+	return nil
+}
+
+func (l *s3ConditionalLocker) GetLockInfo() (*statemgr.LockInfo, error) {
+	ctx := context.TODO()
+
+	output, err := l.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(l.bucket),
+		Key:    aws.String(l.lockKey),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &statemgr.LockInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
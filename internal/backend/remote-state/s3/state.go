@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/hashicorp/terraform/internal/backend"
+	"github.com/hashicorp/terraform/internal/states"
+	"github.com/hashicorp/terraform/internal/states/remote"
+	"github.com/hashicorp/terraform/internal/states/statemgr"
+)
+
+// Workspaces returns the list of state names stored in the bucket, with
+// "default" first followed by the remaining names in lexical order.
+func (b *Backend) Workspaces() ([]string, error) {
+	ctx := context.TODO()
+
+	const maxKeys = 1000
+
+	prefix := strings.TrimSuffix(b.workspaceKeyPrefix, "/") + "/"
+
+	workspaces := map[string]struct{}{
+		backend.DefaultStateName: {},
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(b.s3Client, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(b.bucketName),
+		Prefix:  aws.String(prefix),
+		MaxKeys: aws.Int32(maxKeys),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing workspaces: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			if obj.Key == nil {
+				continue
+			}
+			if ws := b.keyEnv(*obj.Key); ws != "" {
+				workspaces[ws] = struct{}{}
+			}
+		}
+	}
+
+	var result []string
+	for ws := range workspaces {
+		if ws != backend.DefaultStateName {
+			result = append(result, ws)
+		}
+	}
+	sort.Strings(result)
+
+	return append([]string{backend.DefaultStateName}, result...), nil
+}
+
+// DeleteWorkspace deletes the state for the given workspace.
+func (b *Backend) DeleteWorkspace(name string, force bool) error {
+	if name == backend.DefaultStateName || name == "" {
+		return fmt.Errorf("can't delete default state")
+	}
+
+	client := b.remoteClient(name)
+	return client.Delete()
+}
+
+// StateMgr returns a state manager for the given workspace.
+func (b *Backend) StateMgr(name string) (statemgr.Full, error) {
+	client := b.remoteClient(name)
+
+	stateMgr := &remote.State{Client: client}
+
+	if err := stateMgr.RefreshState(); err != nil {
+		return nil, fmt.Errorf("refreshing state: %w", err)
+	}
+
+	if v := stateMgr.State(); v == nil {
+		if err := stateMgr.WriteState(states.NewState()); err != nil {
+			return nil, err
+		}
+		if err := stateMgr.PersistState(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return stateMgr, nil
+}
+
+func (b *Backend) remoteClient(name string) *RemoteClient {
+	path := b.path(name)
+
+	client := &RemoteClient{
+		s3Client:              b.s3Client,
+		bucketName:            b.bucketName,
+		path:                  path,
+		serverSideEncryption:  b.serverSideEncryption,
+		customerEncryptionKey: b.customerEncryptionKey,
+		acl:                   b.acl,
+		kmsKeyID:              b.kmsKeyID,
+	}
+
+	switch b.lockBackend {
+	case lockBackendDynamoDB:
+		client.locker = &dynamodbLocker{
+			client:   b.dynClient,
+			table:    b.ddbTable,
+			lockPath: fmt.Sprintf("%s/%s", b.bucketName, path),
+		}
+	case lockBackendS3:
+		client.locker = &s3ConditionalLocker{
+			client:  b.s3Client,
+			bucket:  b.bucketName,
+			lockKey: lockKeyForPath(path),
+		}
+	}
+
+	return client
+}
@@ -0,0 +1,70 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/internal/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// attributeErrDiag builds a diagnostic anchored to a specific attribute path.
+func attributeErrDiag(summary, detail string, path cty.Path) tfdiags.Diagnostic {
+	return tfdiags.AttributeValue(tfdiags.Error, summary, detail, path)
+}
+
+// requiredAttributeErrDiag reports that a required attribute was not set.
+func requiredAttributeErrDiag(path cty.Path) tfdiags.Diagnostic {
+	return attributeErrDiag(
+		"Missing Required Value",
+		fmt.Sprintf("The attribute %q is required by the backend configuration.", pathString(path)),
+		path,
+	)
+}
+
+// wholeBodyErrDiag builds a diagnostic that isn't anchored to a single
+// attribute, for cases (such as environment variable conflicts) that span
+// more than one configuration value.
+func wholeBodyErrDiag(summary, detail string) tfdiags.Diagnostic {
+	return tfdiags.Sourceless(tfdiags.Error, summary, detail)
+}
+
+const encryptionKeyConflictEnvVarError = `Only one of "kms_key_id" or the "AWS_SSE_CUSTOMER_KEY" environment variable can be set.
+
+The "kms_key_id" argument configures server-side encryption with KMS (SSE-KMS), while the "AWS_SSE_CUSTOMER_KEY" environment variable configures server-side encryption with a customer-provided key (SSE-C). Only one encryption method can be used for a given object.`
+
+// diagnosticComparer is used with cmp.Diff in tests to compare
+// tfdiags.Diagnostic values by their observable content, since the
+// concrete types returned by the tfdiags constructors are not otherwise
+// comparable. This also compares the attribute path a diagnostic is
+// anchored to, so a test asserting on a nested block's diagnostics catches
+// a regression that points at the wrong attribute.
+func diagnosticComparer(l, r tfdiags.Diagnostic) bool {
+	if l.Severity() != r.Severity() {
+		return false
+	}
+	lDesc, rDesc := l.Description(), r.Description()
+	if lDesc.Summary != rDesc.Summary || lDesc.Detail != rDesc.Detail {
+		return false
+	}
+	return tfdiags.GetAttribute(l).Equals(tfdiags.GetAttribute(r))
+}
+
+func pathString(path cty.Path) string {
+	var buf strings.Builder
+	for i, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			if i > 0 {
+				buf.WriteByte('.')
+			}
+			buf.WriteString(s.Name)
+		case cty.IndexStep:
+			fmt.Fprintf(&buf, "[%s]", s.Key.AsString())
+		}
+	}
+	return buf.String()
+}
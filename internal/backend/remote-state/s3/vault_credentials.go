@@ -0,0 +1,422 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/hashicorp/terraform/internal/configs/configschema"
+	"github.com/hashicorp/terraform/internal/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const vaultCredentialsConflictError = `Only one of "access_key"/"secret_key"/"profile" or the "vault_aws_credentials" block can be set.
+
+The "vault_aws_credentials" block reads dynamic AWS credentials from Vault's AWS secrets engine, which is mutually exclusive with providing static credentials or a shared config profile directly.`
+
+var vaultCredentialTypes = map[string]bool{
+	"iam_user":         true,
+	"assumed_role":     true,
+	"federation_token": true,
+}
+
+// vaultAWSCredentialsSchema describes the nested `vault_aws_credentials`
+// configuration block, which reads dynamic AWS credentials from Vault's AWS
+// secrets engine instead of using static keys, a shared profile, or one of
+// the assume-role mechanisms.
+func vaultAWSCredentialsSchema() *configschema.Block {
+	return &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{
+			"address": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The address of the Vault server, e.g. https://vault.example.com:8200. Defaults to the VAULT_ADDR environment variable.",
+			},
+			"token": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The Vault token to authenticate with. Defaults to the VAULT_TOKEN environment variable.",
+			},
+			"token_helper": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "Path to an external helper program that prints a Vault token to stdout.",
+			},
+			"namespace": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The Vault Enterprise namespace to use. Defaults to the VAULT_NAMESPACE environment variable.",
+			},
+			"role": {
+				Type:        cty.String,
+				Required:    true,
+				Description: "The name of the Vault AWS secrets engine role to read credentials for.",
+			},
+			"mount_path": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: `The mount path of the AWS secrets engine. Defaults to "aws".`,
+			},
+			"credential_type": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: `The type of credential to request: "iam_user", "assumed_role", or "federation_token". Defaults to "iam_user".`,
+			},
+			"ttl": {
+				Type:        cty.String,
+				Optional:    true,
+				Description: "The TTL to request for the lease, e.g. \"15m\". Defaults to the role's configured TTL.",
+			},
+		},
+	}
+}
+
+// prepareVaultAWSCredentialsConfig validates the vault_aws_credentials
+// block, anchoring diagnostics at path.
+func prepareVaultAWSCredentialsConfig(obj cty.Value, path cty.Path) tfdiags.Diagnostics {
+	var diags tfdiags.Diagnostics
+
+	if obj.IsNull() {
+		return diags
+	}
+
+	addressPath := path.GetAttr("address")
+	hasAddress := false
+	if val := obj.GetAttr("address"); !val.IsNull() && val.AsString() != "" {
+		hasAddress = true
+	}
+	if !hasAddress && os.Getenv("VAULT_ADDR") == "" {
+		diags = diags.Append(attributeErrDiag(
+			"Missing Vault address",
+			`The "address" attribute or the "VAULT_ADDR" environment variable must be set.`,
+			addressPath,
+		))
+	}
+
+	rolePath := path.GetAttr("role")
+	roleVal := obj.GetAttr("role")
+	if roleVal.IsNull() || roleVal.AsString() == "" {
+		diags = diags.Append(requiredAttributeErrDiag(rolePath))
+	}
+
+	tokenVal := obj.GetAttr("token")
+	tokenHelperVal := obj.GetAttr("token_helper")
+	hasToken := !tokenVal.IsNull() && tokenVal.AsString() != ""
+	hasTokenHelper := !tokenHelperVal.IsNull() && tokenHelperVal.AsString() != ""
+	hasTokenEnv := os.Getenv("VAULT_TOKEN") != ""
+
+	if hasToken && hasTokenHelper {
+		diags = diags.Append(attributeErrDiag(
+			"Invalid Attribute Combination",
+			`Only one of "token" or "token_helper" can be set.`,
+			path,
+		))
+	} else if !hasToken && !hasTokenHelper && !hasTokenEnv {
+		diags = diags.Append(attributeErrDiag(
+			"Missing Required Value",
+			`One of "token" or "token_helper" must be set, or the "VAULT_TOKEN" environment variable.`,
+			path,
+		))
+	}
+
+	if val := obj.GetAttr("credential_type"); !val.IsNull() && val.AsString() != "" {
+		credentialType := val.AsString()
+		if !vaultCredentialTypes[credentialType] {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Value",
+				fmt.Sprintf("The value %q must be one of \"iam_user\", \"assumed_role\", or \"federation_token\"", credentialType),
+				path.GetAttr("credential_type"),
+			))
+		}
+	}
+
+	if val := obj.GetAttr("ttl"); !val.IsNull() && val.AsString() != "" {
+		ttl := val.AsString()
+		if _, err := time.ParseDuration(ttl); err != nil {
+			diags = diags.Append(attributeErrDiag(
+				"Invalid Duration",
+				fmt.Sprintf("The value %q cannot be parsed as a duration: %s", ttl, err),
+				path.GetAttr("ttl"),
+			))
+		}
+	}
+
+	return diags
+}
+
+// vaultLeaseCredentials is the set of AWS credentials and lease metadata
+// read back from Vault's AWS secrets engine.
+type vaultLeaseCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	LeaseID         string
+	LeaseDuration   time.Duration
+	Expiration      time.Time
+}
+
+// vaultAWSCredentialsProvider implements aws.CredentialsProvider, serving
+// credentials obtained from Vault's AWS secrets engine and renewing the
+// underlying lease in the background until Close is called.
+type vaultAWSCredentialsProvider struct {
+	httpClient *http.Client
+
+	address        string
+	token          string
+	namespace      string
+	role           string
+	mountPath      string
+	credentialType string
+	ttl            string
+
+	mu    sync.Mutex
+	creds vaultLeaseCredentials
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// fetched is closed the first time fetchLocked succeeds, so
+	// startRenewLoop can block until there is an actual lease to renew
+	// instead of polling on a fixed timer.
+	fetched     chan struct{}
+	fetchedOnce sync.Once
+}
+
+var _ aws.CredentialsProvider = (*vaultAWSCredentialsProvider)(nil)
+
+// newVaultAWSCredentialsProvider builds a vaultAWSCredentialsProvider from a
+// validated vault_aws_credentials block, applying the VAULT_ADDR,
+// VAULT_TOKEN, and VAULT_NAMESPACE environment variable overrides.
+func newVaultAWSCredentialsProvider(obj cty.Value) *vaultAWSCredentialsProvider {
+	p := &vaultAWSCredentialsProvider{
+		httpClient: http.DefaultClient,
+		mountPath:  "aws",
+		stop:       make(chan struct{}),
+		fetched:    make(chan struct{}),
+	}
+
+	if val := obj.GetAttr("address"); !val.IsNull() {
+		p.address = val.AsString()
+	}
+	if p.address == "" {
+		p.address = os.Getenv("VAULT_ADDR")
+	}
+
+	if val := obj.GetAttr("token"); !val.IsNull() {
+		p.token = val.AsString()
+	}
+	if p.token == "" {
+		p.token = os.Getenv("VAULT_TOKEN")
+	}
+
+	if val := obj.GetAttr("namespace"); !val.IsNull() {
+		p.namespace = val.AsString()
+	}
+	if p.namespace == "" {
+		p.namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	if val := obj.GetAttr("role"); !val.IsNull() {
+		p.role = val.AsString()
+	}
+	if val := obj.GetAttr("mount_path"); !val.IsNull() && val.AsString() != "" {
+		p.mountPath = val.AsString()
+	}
+	if val := obj.GetAttr("credential_type"); !val.IsNull() && val.AsString() != "" {
+		p.credentialType = val.AsString()
+	} else {
+		p.credentialType = "iam_user"
+	}
+	if val := obj.GetAttr("ttl"); !val.IsNull() {
+		p.ttl = val.AsString()
+	}
+
+	return p
+}
+
+// Retrieve implements aws.CredentialsProvider, fetching a lease from Vault
+// if one has not already been obtained.
+func (p *vaultAWSCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.creds.AccessKeyID == "" {
+		if err := p.fetchLocked(ctx); err != nil {
+			return aws.Credentials{}, err
+		}
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     p.creds.AccessKeyID,
+		SecretAccessKey: p.creds.SecretAccessKey,
+		SessionToken:    p.creds.SessionToken,
+		CanExpire:       true,
+		Expires:         p.creds.Expiration,
+		Source:          "VaultAWSCredentialsProvider",
+	}, nil
+}
+
+// vaultCredsEndpoint returns the Vault API path to request credentials
+// from, which depends on the requested credential type.
+func (p *vaultAWSCredentialsProvider) vaultCredsEndpoint() string {
+	if p.credentialType == "assumed_role" || p.credentialType == "federation_token" {
+		return fmt.Sprintf("/v1/%s/sts/%s", p.mountPath, p.role)
+	}
+	return fmt.Sprintf("/v1/%s/creds/%s", p.mountPath, p.role)
+}
+
+type vaultCredsResponse struct {
+	LeaseID       string `json:"lease_id"`
+	LeaseDuration int    `json:"lease_duration"`
+	Data          struct {
+		AccessKey     string `json:"access_key"`
+		SecretKey     string `json:"secret_key"`
+		SecurityToken string `json:"security_token"`
+	} `json:"data"`
+}
+
+// fetchLocked requests a new lease from Vault. Callers must hold p.mu.
+func (p *vaultAWSCredentialsProvider) fetchLocked(ctx context.Context) error {
+	endpoint := p.vaultCredsEndpoint()
+	if p.credentialType != "iam_user" {
+		endpoint += fmt.Sprintf("?ttl=%s", p.ttl)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.address, "/")+endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("building Vault credentials request: %w", err)
+	}
+	p.setVaultHeaders(req)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("requesting AWS credentials from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("requesting AWS credentials from Vault: unexpected status %s", resp.Status)
+	}
+
+	var body vaultCredsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decoding Vault credentials response: %w", err)
+	}
+
+	p.creds = vaultLeaseCredentials{
+		AccessKeyID:     body.Data.AccessKey,
+		SecretAccessKey: body.Data.SecretKey,
+		SessionToken:    body.Data.SecurityToken,
+		LeaseID:         body.LeaseID,
+		LeaseDuration:   time.Duration(body.LeaseDuration) * time.Second,
+		Expiration:      time.Now().Add(time.Duration(body.LeaseDuration) * time.Second),
+	}
+	p.fetchedOnce.Do(func() { close(p.fetched) })
+
+	return nil
+}
+
+// renew renews the current lease via Vault's generic lease renewal
+// endpoint, extending its expiration.
+func (p *vaultAWSCredentialsProvider) renew(ctx context.Context) error {
+	p.mu.Lock()
+	leaseID := p.creds.LeaseID
+	p.mu.Unlock()
+
+	if leaseID == "" {
+		return nil
+	}
+
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"lease_id": leaseID,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimSuffix(p.address, "/")+"/v1/sys/leases/renew", strings.NewReader(string(reqBody)))
+	if err != nil {
+		return fmt.Errorf("building Vault lease renewal request: %w", err)
+	}
+	p.setVaultHeaders(req)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("renewing Vault lease: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("renewing Vault lease: unexpected status %s", resp.Status)
+	}
+
+	var body vaultCredsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("decoding Vault lease renewal response: %w", err)
+	}
+
+	p.mu.Lock()
+	p.creds.LeaseDuration = time.Duration(body.LeaseDuration) * time.Second
+	p.creds.Expiration = time.Now().Add(time.Duration(body.LeaseDuration) * time.Second)
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *vaultAWSCredentialsProvider) setVaultHeaders(req *http.Request) {
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+}
+
+// startRenewLoop runs until Close is called, renewing the lease shortly
+// before it expires. It does nothing until the first lease has been
+// fetched by Retrieve, since there is nothing to renew before then.
+func (p *vaultAWSCredentialsProvider) startRenewLoop() {
+	go func() {
+		select {
+		case <-p.stop:
+			return
+		case <-p.fetched:
+		}
+
+		for {
+			p.mu.Lock()
+			expiration := p.creds.Expiration
+			p.mu.Unlock()
+
+			wait := time.Until(expiration) - 30*time.Second
+			if wait <= 0 {
+				wait = time.Second
+			}
+
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(wait):
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				_ = p.renew(ctx)
+				cancel()
+			}
+		}
+	}()
+}
+
+// Close stops the background lease renewal goroutine. It is safe to call
+// more than once.
+func (p *vaultAWSCredentialsProvider) Close() error {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	return nil
+}